@@ -0,0 +1,159 @@
+package exif
+
+import (
+	"io"
+
+	xjpeg "github.com/tajtiattila/metadata/jpeg"
+)
+
+var iccChunkHeader = []byte("\xff\xe2--ICC_PROFILE\x00")
+
+// StripOptions configures Strip.
+type StripOptions struct {
+	// KeepICC preserves the ICC profile (APP2) segment, if present,
+	// instead of dropping it.
+	KeepICC bool
+
+	// KeepOrientation re-encodes the Exif APP1 segment with every
+	// IFD0 tag removed except Orientation, instead of dropping Exif
+	// metadata entirely.
+	KeepOrientation bool
+}
+
+// Strip copies r to w, keeping only the segments needed to decode
+// the image (SOI, DQT, DHT, SOFn, SOS, EOI, and JFIF) and dropping
+// everything else, including Exif, ICC, Photoshop/IPTC, XMP, COM and
+// any segment Strip does not recognise. Unlike Copy, which preserves
+// unknown segments, Strip is a positive whitelist, so unexpected
+// APPn markers that might carry personal data are removed by
+// default. opts can be used to opt back into keeping the ICC profile
+// or an orientation-only Exif segment.
+func Strip(w io.Writer, r io.Reader, opts StripOptions) error {
+	j, err := xjpeg.NewScanner(r)
+	if err != nil {
+		return err
+	}
+
+	if _, err := w.Write([]byte{0xff, 0xd8}); err != nil {
+		return err
+	}
+
+	for j.Next() {
+		header := j.Bytes()
+
+		if isDecodeSegment(header) {
+			chunk, err := j.ReadChunk()
+			if err != nil {
+				return err
+			}
+			if _, err := w.Write(chunk); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if opts.KeepICC && cmpChunkHeader(header, iccChunkHeader) {
+			chunk, err := j.ReadChunk()
+			if err != nil {
+				return err
+			}
+			if _, err := w.Write(chunk); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if opts.KeepOrientation && cmpChunkHeader(header, exifChunkHeader) {
+			chunk, err := j.ReadChunk()
+			if err != nil {
+				return err
+			}
+			stripped, err := orientationOnlyChunk(chunk)
+			if err != nil {
+				return err
+			}
+			if stripped != nil {
+				if _, err := w.Write(stripped); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		// unrecognised, or explicitly excluded, segment: drop it
+	}
+	if err := j.Err(); err != nil {
+		return err
+	}
+
+	// SOS, the entropy-coded scan data and EOI are always kept; they
+	// are never surfaced as segments by the scanner and are copied
+	// verbatim here
+	_, err = io.Copy(w, j.Reader())
+	return err
+}
+
+// isDecodeSegment reports whether header (as returned by
+// xjpeg.Scanner.Bytes) is a segment required to decode the image:
+// DQT, DHT, a SOFn frame header, or JFIF.
+func isDecodeSegment(header []byte) bool {
+	if len(header) < 2 || header[0] != 0xff {
+		return false
+	}
+	switch m := header[1]; {
+	case m == 0xdb: // DQT
+		return true
+	case m == 0xc4: // DHT
+		return true
+	case m >= 0xc0 && m <= 0xcf && m != 0xc4 && m != 0xc8 && m != 0xcc: // SOFn
+		return true
+	}
+	return cmpChunkHeader(header, jfifChunkHeader)
+}
+
+// orientationOnlyChunk decodes chunk, an APP1/Exif segment, and
+// re-encodes it with every IFD0 tag removed except Orientation. It
+// returns nil if the segment has no Orientation tag.
+func orientationOnlyChunk(chunk []byte) ([]byte, error) {
+	data, ok, err := OrientationOnlyBytes(chunk[len(exifChunkHeader):])
+	if err != nil || !ok {
+		return nil, err
+	}
+
+	var chunkBuf []byte
+	err = xjpeg.WriteChunk(sliceWriter{&chunkBuf}, 0xe1, data)
+	return chunkBuf, err
+}
+
+// OrientationOnlyBytes decodes raw, a raw TIFF/Exif payload such as
+// the one DecodeBytes accepts, and re-encodes it with every IFD0 tag
+// removed except Orientation. It reports ok false, with no error, if
+// raw has no Orientation tag. Callers embedding Exif data in other
+// containers (such as png.Strip, for the PNG eXIf chunk) can use this
+// to support an orientation-only KeepOrientation mode without
+// depending on exif's own segment framing.
+func OrientationOnlyBytes(raw []byte) (data []byte, ok bool, err error) {
+	x, err := DecodeBytes(raw)
+	if err != nil {
+		return nil, false, err
+	}
+
+	orientation, ok := x.IFD0[TagOrientation]
+	if !ok {
+		return nil, false, nil
+	}
+
+	stripped := &Exif{
+		ByteOrder: x.ByteOrder,
+		IFD0:      Dir{TagOrientation: orientation},
+	}
+	data, err = stripped.EncodeBytes()
+	return data, true, err
+}
+
+type sliceWriter struct{ p *[]byte }
+
+func (w sliceWriter) Write(p []byte) (int, error) {
+	*w.p = append(*w.p, p...)
+	return len(p), nil
+}
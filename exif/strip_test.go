@@ -0,0 +1,176 @@
+package exif
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+// segment builds a marker segment with the given marker byte and
+// payload, writing the standard 2-byte big-endian length (including
+// the length field itself).
+func segment(marker byte, payload []byte) []byte {
+	l := len(payload) + 2
+	return append([]byte{0xff, marker, byte(l >> 8), byte(l)}, payload...)
+}
+
+var (
+	dqtSeg  = segment(0xdb, []byte{0x00, 0x01, 0x02, 0x03})
+	dhtSeg  = segment(0xc4, []byte{0x00, 0x01, 0x02, 0x03})
+	sof0Seg = segment(0xc0, []byte{
+		0x08,       // bit depth
+		0x00, 0x01, // height
+		0x00, 0x01, // width
+		0x01,             // 1 component
+		0x01, 0x11, 0x00, // component 1
+	})
+
+	// sosAndTail is SOS, one byte of "entropy-coded" scan data and
+	// EOI: the part of a JPEG stream that Strip always keeps without
+	// ever surfacing it as a segment.
+	sosAndTail = append(segment(0xda, []byte{
+		0x01,
+		0x01, 0x00,
+		0x00, 0x3f, 0x00,
+	}), 0x00, 0xff, 0xd9)
+)
+
+// jpegStream concatenates SOI, the given segments, and sosAndTail
+// into a complete, minimal JPEG byte stream.
+func jpegStream(segments ...[]byte) []byte {
+	buf := []byte{0xff, 0xd8}
+	for _, s := range segments {
+		buf = append(buf, s...)
+	}
+	return append(buf, sosAndTail...)
+}
+
+func exifSeg(raw []byte) []byte {
+	return segment(0xe1, append([]byte("Exif\x00\x00"), raw...))
+}
+
+func iccSeg(data []byte) []byte {
+	return segment(0xe2, append([]byte("ICC_PROFILE\x00"), data...))
+}
+
+// segmentPayload returns the payload of the first segment in data
+// with the given marker, excluding its marker and length bytes.
+func segmentPayload(t *testing.T, data []byte, marker byte) []byte {
+	t.Helper()
+	for i := 0; i+4 <= len(data); i++ {
+		if data[i] != 0xff || data[i+1] != marker {
+			continue
+		}
+		l := int(data[i+2])<<8 | int(data[i+3])
+		return data[i+4 : i+2+l]
+	}
+	return nil
+}
+
+func TestStripDefaultKeepsOnlyDecodeSegments(t *testing.T) {
+	src := jpegStream(
+		dqtSeg, dhtSeg, sof0Seg,
+		exifSeg([]byte{0x01, 0x02, 0x03, 0x04}),
+		iccSeg([]byte{0x01, 0x02, 0x03, 0x04}),
+		segment(0xed, []byte("Photoshop 3.0\x00junk")), // APP13
+		segment(0xfe, []byte("a comment")),             // COM
+		segment(0xe5, []byte("an unrecognised APPn")),  // unrecognised APPn
+	)
+	want := jpegStream(dqtSeg, dhtSeg, sof0Seg)
+
+	var out bytes.Buffer
+	if err := Strip(&out, bytes.NewReader(src), StripOptions{}); err != nil {
+		t.Fatalf("Strip: %v", err)
+	}
+	if !bytes.Equal(out.Bytes(), want) {
+		t.Fatalf("Strip output mismatch:\n got  %x\nwant  %x", out.Bytes(), want)
+	}
+}
+
+func TestStripKeepICCPreservesICCProfile(t *testing.T) {
+	icc := iccSeg([]byte{0x01, 0x02, 0x03, 0x04})
+	src := jpegStream(dqtSeg, icc, exifSeg([]byte{0x01, 0x02, 0x03, 0x04}))
+	want := jpegStream(dqtSeg, icc)
+
+	var out bytes.Buffer
+	if err := Strip(&out, bytes.NewReader(src), StripOptions{KeepICC: true}); err != nil {
+		t.Fatalf("Strip: %v", err)
+	}
+	if !bytes.Equal(out.Bytes(), want) {
+		t.Fatalf("Strip output mismatch:\n got  %x\nwant  %x", out.Bytes(), want)
+	}
+}
+
+// tiffIFD0 builds a minimal big-endian TIFF/Exif payload with one
+// IFD0 containing a Make tag (so a real tag is present to be
+// stripped) and, if withOrientation, an Orientation tag set to
+// orientation.
+func tiffIFD0(withOrientation bool, orientation uint16) []byte {
+	entries := [][]byte{
+		{0x01, 0x0f, 0x00, 0x02, 0x00, 0x00, 0x00, 0x01, 'M', 0x00, 0x00, 0x00}, // Make, ASCII
+	}
+	if withOrientation {
+		entries = append(entries, []byte{
+			0x01, 0x12, 0x00, 0x03, 0x00, 0x00, 0x00, 0x01,
+			byte(orientation >> 8), byte(orientation), 0x00, 0x00,
+		})
+	}
+
+	buf := []byte{'M', 'M', 0x00, 0x2a, 0x00, 0x00, 0x00, 0x08}
+	buf = append(buf, byte(len(entries)>>8), byte(len(entries)))
+	for _, e := range entries {
+		buf = append(buf, e...)
+	}
+	return append(buf, 0x00, 0x00, 0x00, 0x00) // next IFD offset
+}
+
+func TestStripKeepOrientationTrimsIFD0(t *testing.T) {
+	src := jpegStream(dqtSeg, exifSeg(tiffIFD0(true, 5)))
+
+	var out bytes.Buffer
+	if err := Strip(&out, bytes.NewReader(src), StripOptions{KeepOrientation: true}); err != nil {
+		t.Fatalf("Strip: %v", err)
+	}
+
+	payload := segmentPayload(t, out.Bytes(), 0xe1)
+	if payload == nil {
+		t.Fatal("expected an Exif (APP1) segment in the output")
+	}
+	x, err := DecodeBytes(payload[len("Exif\x00\x00"):])
+	if err != nil {
+		t.Fatalf("DecodeBytes: %v", err)
+	}
+	if len(x.IFD0) != 1 {
+		t.Fatalf("got %d IFD0 tags, want 1 (Orientation only): %+v", len(x.IFD0), x.IFD0)
+	}
+	v, ok := x.IFD0[TagOrientation]
+	if !ok || fmt.Sprint(v) != "5" {
+		t.Fatalf("got Orientation=%v ok=%v, want 5", v, ok)
+	}
+}
+
+func TestStripKeepOrientationDropsSegmentWithoutOrientation(t *testing.T) {
+	src := jpegStream(dqtSeg, exifSeg(tiffIFD0(false, 0)))
+	want := jpegStream(dqtSeg)
+
+	var out bytes.Buffer
+	if err := Strip(&out, bytes.NewReader(src), StripOptions{KeepOrientation: true}); err != nil {
+		t.Fatalf("Strip: %v", err)
+	}
+	if !bytes.Equal(out.Bytes(), want) {
+		t.Fatalf("Strip output mismatch:\n got  %x\nwant  %x", out.Bytes(), want)
+	}
+}
+
+func TestStripNeverTouchesBytesAfterEOI(t *testing.T) {
+	trailer := []byte("trailing bytes after EOI must survive untouched")
+	src := append(jpegStream(dqtSeg), trailer...)
+
+	var out bytes.Buffer
+	if err := Strip(&out, bytes.NewReader(src), StripOptions{}); err != nil {
+		t.Fatalf("Strip: %v", err)
+	}
+	if !bytes.HasSuffix(out.Bytes(), trailer) {
+		t.Fatalf("bytes after EOI were not preserved unchanged")
+	}
+}
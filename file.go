@@ -1,8 +1,10 @@
 package metadata
 
 import (
+	"errors"
 	"io"
 	"io/ioutil"
+	"sort"
 )
 
 // FileOp represents a change that should be applied to a stream.
@@ -159,3 +161,140 @@ func zerofill(p []byte, maxfill int) int {
 	}
 	return n
 }
+
+// opSegment locates one FileOp's replacement data within the output
+// stream produced by applying ops to a source of a given size.
+type opSegment struct {
+	op FileOp
+
+	outStart int64 // output offset where op.Data begins
+	outEnd   int64 // output offset where op.Data ends
+
+	deltaAfter int64 // cumulative (len(Data)-Size) after this op
+}
+
+// segments returns ops sorted by Offset, annotated with their
+// position in the output stream.
+func (ops FileOps) segments() []opSegment {
+	sorted := make([]FileOp, len(ops))
+	copy(sorted, ops)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Offset < sorted[j].Offset })
+
+	segs := make([]opSegment, len(sorted))
+	var delta int64
+	for i, o := range sorted {
+		outStart := o.Offset + delta
+		delta += int64(len(o.Data)) - int64(o.Size)
+		segs[i] = opSegment{
+			op:         o,
+			outStart:   outStart,
+			outEnd:     outStart + int64(len(o.Data)),
+			deltaAfter: delta,
+		}
+	}
+	return segs
+}
+
+// ReaderAt returns an io.ReadSeeker presenting the result of applying
+// ops to r, which has the given size. The returned stream computes
+// its length from size and ops (the original size plus the sum of
+// len(Data)-Size over ops), and translates any output offset back
+// into an offset into r, or into an op's Data, with a binary search
+// over ops, so it never needs to buffer r or the transformed stream.
+// This makes it suitable for muxing modified files into HTTP range
+// responses or zip archives.
+func (ops FileOps) ReaderAt(r io.ReaderAt, size int64) io.ReadSeeker {
+	segs := ops.segments()
+	var delta int64
+	if n := len(segs); n > 0 {
+		delta = segs[n-1].deltaAfter
+	}
+	return &opSectionReader{r: r, segs: segs, size: size + delta}
+}
+
+type opSectionReader struct {
+	r    io.ReaderAt
+	segs []opSegment
+	size int64
+	off  int64
+}
+
+func (s *opSectionReader) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+		// offset is already relative to the start
+	case io.SeekCurrent:
+		offset += s.off
+	case io.SeekEnd:
+		offset += s.size
+	default:
+		return 0, errors.New("metadata: FileOps.ReaderAt: invalid whence")
+	}
+	if offset < 0 {
+		return 0, errors.New("metadata: FileOps.ReaderAt: negative position")
+	}
+	s.off = offset
+	return offset, nil
+}
+
+func (s *opSectionReader) Read(p []byte) (int, error) {
+	if s.off >= s.size {
+		return 0, io.EOF
+	}
+	if max := s.size - s.off; int64(len(p)) > max {
+		p = p[:max]
+	}
+
+	// i is the index of the first op whose Data begins after s.off
+	i := sort.Search(len(s.segs), func(i int) bool {
+		return s.segs[i].outStart > s.off
+	})
+
+	if i > 0 && s.off < s.segs[i-1].outEnd {
+		// s.off falls inside the previous op's replacement data
+		seg := s.segs[i-1]
+		n := copy(p, seg.op.Data[s.off-seg.outStart:])
+		s.off += int64(n)
+		return n, nil
+	}
+
+	// s.off falls in a passthrough region; translate to an offset
+	// into r and don't read past the next op's Data, if any
+	var delta int64
+	if i > 0 {
+		delta = s.segs[i-1].deltaAfter
+	}
+	if i < len(s.segs) {
+		if max := s.segs[i].outStart - s.off; int64(len(p)) > max {
+			p = p[:max]
+		}
+	}
+
+	n, err := s.r.ReadAt(p, s.off-delta)
+	s.off += int64(n)
+	if err == io.EOF && n > 0 {
+		err = nil
+	}
+	return n, err
+}
+
+// WriteTo implements io.WriterTo, writing the whole remaining stream
+// to w through a single 32 KiB buffer.
+func (s *opSectionReader) WriteTo(w io.Writer) (int64, error) {
+	var total int64
+	buf := make([]byte, 32*1024)
+	for s.off < s.size {
+		n, err := s.Read(buf)
+		if n > 0 {
+			wn, werr := w.Write(buf[:n])
+			total += int64(wn)
+			if werr != nil {
+				return total, werr
+			}
+		}
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
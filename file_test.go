@@ -0,0 +1,138 @@
+package metadata
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+// readAt seeks r to off and reads exactly len(p) bytes, or until EOF,
+// mirroring the shape of an io.ReaderAt call without requiring the
+// returned io.ReadSeeker to implement ReaderAt itself.
+func readAt(t *testing.T, r io.ReadSeeker, off int64, p []byte) []byte {
+	t.Helper()
+	if _, err := r.Seek(off, io.SeekStart); err != nil {
+		t.Fatalf("Seek: %v", err)
+	}
+	n, err := io.ReadFull(r, p)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		t.Fatalf("Read: %v", err)
+	}
+	return p[:n]
+}
+
+func TestReaderAtReadWithinOpData(t *testing.T) {
+	src := bytes.NewReader([]byte("0123456789"))
+	ops := FileOps{{Offset: 2, Size: 3, Data: []byte("XYZ")}}
+	r := ops.ReaderAt(src, int64(src.Len()))
+
+	got := readAt(t, r, 3, make([]byte, 2))
+	if string(got) != "YZ" {
+		t.Fatalf("got %q, want %q", got, "YZ")
+	}
+}
+
+func TestReaderAtReadSpansPassthroughBetweenOps(t *testing.T) {
+	src := bytes.NewReader([]byte("0123456789"))
+	ops := FileOps{
+		{Offset: 0, Size: 1, Data: []byte("A")},
+		{Offset: 9, Size: 1, Data: []byte("B")},
+	}
+	r := ops.ReaderAt(src, int64(src.Len()))
+
+	got := readAt(t, r, 0, make([]byte, 10))
+	want := "A12345678B"
+	if string(got) != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+
+	// A read starting mid-passthrough and running up to the next op's
+	// replacement data must stop exactly at the op boundary, not spill
+	// into it.
+	if _, err := r.Seek(3, io.SeekStart); err != nil {
+		t.Fatalf("Seek: %v", err)
+	}
+	buf := make([]byte, 10)
+	n, err := r.Read(buf)
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	if string(buf[:n]) != "345678" {
+		t.Fatalf("got %q, want %q", buf[:n], "345678")
+	}
+}
+
+func TestReaderAtSeek(t *testing.T) {
+	src := bytes.NewReader([]byte("0123456789"))
+	ops := FileOps{{Offset: 4, Size: 2, Data: []byte("XY")}}
+	r := ops.ReaderAt(src, int64(src.Len()))
+
+	if pos, err := r.Seek(2, io.SeekStart); err != nil || pos != 2 {
+		t.Fatalf("Seek(SeekStart): pos=%d err=%v", pos, err)
+	}
+	if b := readN(t, r, 1); string(b) != "2" {
+		t.Fatalf("got %q after SeekStart, want %q", b, "2")
+	}
+
+	if pos, err := r.Seek(2, io.SeekCurrent); err != nil || pos != 5 {
+		t.Fatalf("Seek(SeekCurrent): pos=%d err=%v", pos, err)
+	}
+	if b := readN(t, r, 1); string(b) != "Y" {
+		t.Fatalf("got %q after SeekCurrent, want %q", b, "Y")
+	}
+
+	if pos, err := r.Seek(-1, io.SeekEnd); err != nil || pos != 9 {
+		t.Fatalf("Seek(SeekEnd): pos=%d err=%v", pos, err)
+	}
+	if b := readN(t, r, 1); string(b) != "9" {
+		t.Fatalf("got %q after SeekEnd, want %q", b, "9")
+	}
+}
+
+func readN(t *testing.T, r io.Reader, n int) []byte {
+	t.Helper()
+	buf := make([]byte, n)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	return buf
+}
+
+func TestReaderAtSeekPastEOF(t *testing.T) {
+	src := bytes.NewReader([]byte("0123456789"))
+	ops := FileOps{{Offset: 4, Size: 2, Data: []byte("XY")}}
+	r := ops.ReaderAt(src, int64(src.Len()))
+
+	if _, err := r.Seek(100, io.SeekStart); err != nil {
+		t.Fatalf("Seek past EOF: %v", err)
+	}
+	n, err := r.Read(make([]byte, 4))
+	if n != 0 || err != io.EOF {
+		t.Fatalf("Read past EOF: n=%d err=%v, want n=0 err=io.EOF", n, err)
+	}
+}
+
+func TestReaderAtMultiOpGrowingAndShrinking(t *testing.T) {
+	// Op 0 grows the stream (1 byte -> 3 bytes), op 1 shrinks it
+	// (3 bytes -> 1 byte), exercising the cumulative delta math
+	// across more than one op.
+	src := bytes.NewReader([]byte("0123456789"))
+	ops := FileOps{
+		{Offset: 1, Size: 1, Data: []byte("AAA")},
+		{Offset: 5, Size: 3, Data: []byte("B")},
+	}
+	r := ops.ReaderAt(src, int64(src.Len()))
+
+	want := "0AAA234B89"
+	got := readAt(t, r, 0, make([]byte, len(want)))
+	if string(got) != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+
+	// A read entirely after both ops must be translated back to the
+	// source with the final cumulative delta applied.
+	got = readAt(t, r, int64(len(want))-2, make([]byte, 2))
+	if string(got) != "89" {
+		t.Fatalf("got %q, want %q", got, "89")
+	}
+}
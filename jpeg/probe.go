@@ -0,0 +1,224 @@
+package jpeg
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"io/ioutil"
+)
+
+var (
+	ErrNotJPEG  = errors.New("jpeg: not a JPEG file")
+	ErrNoSOF    = errors.New("jpeg: no frame header found")
+	ErrShortSeg = errors.New("jpeg: segment shorter than its header")
+	ErrDesync   = errors.New("jpeg: corrupt or misaligned marker segment")
+)
+
+// ProbeInfo holds the image geometry and orientation recovered by
+// Probe.
+type ProbeInfo struct {
+	Width, Height int
+	NumComponents int
+	BitDepth      int
+
+	// Orientation is the value of the Exif Orientation tag (1-8), or
+	// 0 if no Exif APP1 segment with an Orientation tag was found
+	// before the start of scan.
+	Orientation int
+}
+
+// Probe scans r for the SOFn frame header and, if present before the
+// start of scan, an Exif APP1 Orientation tag, returning the image's
+// dimensions, component count, bit depth and orientation. It scans
+// only marker segments, using their 2-byte length to skip each one
+// it does not need, and stops at SOS without decoding any
+// entropy-coded scan data. All SOFn variants (baseline, extended
+// sequential, progressive, lossless and arithmetic coding) are
+// recognised.
+//
+// Probe complements xjpeg.Scanner, which exposes raw chunks but no
+// structured frame info.
+func Probe(r io.Reader) (ProbeInfo, error) {
+	br := bufio.NewReader(r)
+
+	var soi [2]byte
+	if _, err := io.ReadFull(br, soi[:]); err != nil {
+		return ProbeInfo{}, err
+	}
+	if soi[0] != 0xff || soi[1] != 0xd8 {
+		return ProbeInfo{}, ErrNotJPEG
+	}
+
+	var info ProbeInfo
+	var haveFrame bool
+	for {
+		m, err := nextMarker(br)
+		if err != nil {
+			return ProbeInfo{}, err
+		}
+		if m == 0xd9 || m == 0xda { // EOI, SOS
+			break
+		}
+
+		length, err := readSegmentLength(br)
+		if err != nil {
+			return ProbeInfo{}, err
+		}
+		payload := length - 2
+
+		switch {
+		case isSOF(m):
+			if err := readSOF(br, payload, &info); err != nil {
+				return ProbeInfo{}, err
+			}
+			haveFrame = true
+		case m == 0xe1: // APP1, possibly Exif
+			o, err := readOrientation(br, payload)
+			if err != nil {
+				return ProbeInfo{}, err
+			}
+			if o != 0 && info.Orientation == 0 {
+				info.Orientation = o
+			}
+		default:
+			if err := discard(br, payload); err != nil {
+				return ProbeInfo{}, err
+			}
+		}
+	}
+
+	if !haveFrame {
+		return ProbeInfo{}, ErrNoSOF
+	}
+	return info, nil
+}
+
+// nextMarker returns the marker byte of the next segment, skipping
+// any fill bytes (0xff) that precede it. The byte immediately
+// following a segment must itself be 0xff: anything else means the
+// stream is corrupt or Probe has lost sync with it, which is
+// reported as ErrDesync rather than silently scanned past, since
+// Probe exists to pre-flight untrusted input.
+func nextMarker(r *bufio.Reader) (byte, error) {
+	b, err := r.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+	if b != 0xff {
+		return 0, ErrDesync
+	}
+	for {
+		b, err = r.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		if b != 0xff {
+			break
+		}
+	}
+	if b == 0 {
+		return 0, ErrDesync // stuffed data byte where a marker was expected
+	}
+	return b, nil
+}
+
+func readSegmentLength(r io.Reader) (int, error) {
+	var b [2]byte
+	if _, err := io.ReadFull(r, b[:]); err != nil {
+		return 0, err
+	}
+	l := int(b[0])<<8 | int(b[1])
+	if l < 2 {
+		return 0, ErrShortSeg
+	}
+	return l, nil
+}
+
+// isSOF reports whether m is a SOFn frame header marker: 0xc0-0xcf,
+// excluding DHT (0xc4), JPG extension (0xc8) and DAC (0xcc).
+func isSOF(m byte) bool {
+	return m >= 0xc0 && m <= 0xcf && m != 0xc4 && m != 0xc8 && m != 0xcc
+}
+
+func readSOF(r io.Reader, payload int, info *ProbeInfo) error {
+	if payload < 6 {
+		return ErrShortSeg
+	}
+	var hdr [6]byte
+	if _, err := io.ReadFull(r, hdr[:]); err != nil {
+		return err
+	}
+	info.BitDepth = int(hdr[0])
+	info.Height = int(hdr[1])<<8 | int(hdr[2])
+	info.Width = int(hdr[3])<<8 | int(hdr[4])
+	info.NumComponents = int(hdr[5])
+	return discard(r, payload-6)
+}
+
+var exifAPP1Header = []byte("Exif\x00\x00")
+
+// readOrientation consumes exactly payload bytes of an APP1 segment,
+// returning the Exif Orientation tag value found in it, or 0 if the
+// segment is not Exif or has no Orientation tag.
+func readOrientation(r io.Reader, payload int) (int, error) {
+	buf := make([]byte, payload)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return 0, err
+	}
+	if len(buf) < len(exifAPP1Header) || !bytes.Equal(buf[:len(exifAPP1Header)], exifAPP1Header) {
+		return 0, nil
+	}
+	return parseOrientation(buf[len(exifAPP1Header):])
+}
+
+const orientationTag = 0x0112
+
+// parseOrientation looks up the Orientation tag in a TIFF-encoded
+// IFD0, returning 0 if not found or if tiff is malformed.
+func parseOrientation(tiff []byte) (int, error) {
+	if len(tiff) < 8 {
+		return 0, nil
+	}
+
+	var bo binary.ByteOrder
+	switch {
+	case bytes.Equal(tiff[:2], []byte("II")):
+		bo = binary.LittleEndian
+	case bytes.Equal(tiff[:2], []byte("MM")):
+		bo = binary.BigEndian
+	default:
+		return 0, nil
+	}
+	if bo.Uint16(tiff[2:4]) != 42 {
+		return 0, nil
+	}
+
+	off := int(bo.Uint32(tiff[4:8]))
+	if off+2 > len(tiff) {
+		return 0, nil
+	}
+
+	const entrySize = 12
+	n := int(bo.Uint16(tiff[off:]))
+	base := off + 2
+	for i := 0; i < n; i++ {
+		e := tiff[base+i*entrySize:]
+		if len(e) < entrySize {
+			break
+		}
+		if bo.Uint16(e[0:2]) == orientationTag {
+			return int(bo.Uint16(e[8:10])), nil
+		}
+	}
+	return 0, nil
+}
+
+func discard(r io.Reader, n int) error {
+	if n <= 0 {
+		return nil
+	}
+	_, err := io.CopyN(ioutil.Discard, r, int64(n))
+	return err
+}
@@ -0,0 +1,65 @@
+package jpeg
+
+import (
+	"bytes"
+	"testing"
+)
+
+// baselineJPEG is a minimal, synthetic JPEG: SOI, a SOF0 frame header
+// (8-bit, 480x640, 3 components), SOS, one byte of "scan data", EOI.
+func baselineJPEG() []byte {
+	return []byte{
+		0xff, 0xd8, // SOI
+
+		0xff, 0xc0, 0x00, 0x11, // SOF0, length 17
+		0x08,       // bit depth
+		0x02, 0x80, // height 640
+		0x01, 0xe0, // width 480
+		0x03,             // 3 components
+		0x01, 0x22, 0x00, // component 1
+		0x02, 0x11, 0x01, // component 2
+		0x03, 0x11, 0x01, // component 3
+
+		0xff, 0xda, 0x00, 0x0c, // SOS, length 12
+		0x03,
+		0x01, 0x00,
+		0x02, 0x11,
+		0x03, 0x11,
+		0x00, 0x3f, 0x00, // Ss, Se, Ah/Al
+
+		0x00, // one byte of "entropy-coded" scan data
+
+		0xff, 0xd9, // EOI
+	}
+}
+
+func TestProbeBaseline(t *testing.T) {
+	info, err := Probe(bytes.NewReader(baselineJPEG()))
+	if err != nil {
+		t.Fatalf("Probe: %v", err)
+	}
+	if info.Width != 480 || info.Height != 640 {
+		t.Fatalf("got %dx%d, want 480x640", info.Width, info.Height)
+	}
+	if info.NumComponents != 3 {
+		t.Fatalf("got %d components, want 3", info.NumComponents)
+	}
+	if info.BitDepth != 8 {
+		t.Fatalf("got bit depth %d, want 8", info.BitDepth)
+	}
+}
+
+func TestProbeDesyncIsAnError(t *testing.T) {
+	data := baselineJPEG()
+
+	// Splice a COM segment, then corrupt the byte where the next
+	// marker's leading 0xff should be, simulating a segment whose
+	// declared length doesn't match its actual content.
+	com := []byte{0xff, 0xfe, 0x00, 0x04, 'h', 'i'}
+	corrupt := append(append([]byte{0xff, 0xd8}, com...), data[2:]...)
+	corrupt[len(com)+2] = 0x00 // clobber the SOF0 segment's leading 0xff
+
+	if _, err := Probe(bytes.NewReader(corrupt)); err != ErrDesync {
+		t.Fatalf("Probe on desynced input: got err=%v, want ErrDesync", err)
+	}
+}
@@ -0,0 +1,74 @@
+package metadata
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+
+	"github.com/tajtiattila/metadata/exif"
+	"github.com/tajtiattila/metadata/png"
+)
+
+var pngSignature = []byte{0x89, 'P', 'N', 'G', '\r', '\n', 0x1a, '\n'}
+
+// Decode decodes Exif metadata from r, which must be a JPEG or PNG
+// file. The container format is detected automatically.
+func Decode(r io.Reader) (*exif.Exif, error) {
+	br := bufio.NewReader(r)
+	isPNG, err := peekPNG(br)
+	if err != nil {
+		return nil, err
+	}
+	if isPNG {
+		return png.Decode(br)
+	}
+	return exif.Decode(br)
+}
+
+// Copy copies r to w, replacing its Exif metadata with x. If x is
+// nil, no Exif metadata is written to w. r must be a JPEG or PNG
+// file; the container format is detected automatically and
+// preserved in the output.
+func Copy(w io.Writer, r io.Reader, x *exif.Exif) error {
+	br := bufio.NewReader(r)
+	isPNG, err := peekPNG(br)
+	if err != nil {
+		return err
+	}
+	if isPNG {
+		return png.Copy(w, br, x)
+	}
+	return exif.Copy(w, br, x)
+}
+
+// StripOptions configures Strip. It mirrors exif.StripOptions.
+type StripOptions = exif.StripOptions
+
+// Strip copies r to w, dropping all metadata except what is needed
+// to decode the image; see exif.StripOptions for exceptions. r must
+// be a JPEG or PNG file; the container format is detected
+// automatically and preserved in the output.
+func Strip(w io.Writer, r io.Reader, opts StripOptions) error {
+	br := bufio.NewReader(r)
+	isPNG, err := peekPNG(br)
+	if err != nil {
+		return err
+	}
+	if isPNG {
+		return png.Strip(w, br, opts)
+	}
+	return exif.Strip(w, br, opts)
+}
+
+// peekPNG reports whether br starts with the PNG signature, without
+// consuming any bytes from it.
+func peekPNG(br *bufio.Reader) (bool, error) {
+	sig, err := br.Peek(len(pngSignature))
+	if err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return false, nil
+		}
+		return false, err
+	}
+	return bytes.Equal(sig, pngSignature), nil
+}
@@ -0,0 +1,180 @@
+package png
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"testing"
+
+	"github.com/tajtiattila/metadata/exif"
+)
+
+// rawChunk builds a complete, self-describing PNG chunk (length, type,
+// data, CRC32) from typ and data, for assembling synthetic PNGs.
+func rawChunk(typ [4]byte, data []byte) []byte {
+	var buf bytes.Buffer
+	writeChunk(&buf, typ, data)
+	return buf.Bytes()
+}
+
+// samplePNG returns a synthetic, minimal-but-plausible PNG: IHDR, an
+// eXIf chunk (omitted if exifData is nil), an unrelated ancillary
+// chunk (pHYs), IDAT, and IEND.
+func samplePNG(exifData []byte) []byte {
+	buf := append([]byte{}, pngSignature...)
+	buf = append(buf, rawChunk(ihdrChunkType, []byte{
+		0, 0, 0, 1, // width
+		0, 0, 0, 1, // height
+		8, 2, 0, 0, 0, // bit depth, color type, compression, filter, interlace
+	})...)
+	if exifData != nil {
+		buf = append(buf, rawChunk(exifChunkType, exifData)...)
+	}
+	buf = append(buf, rawChunk([4]byte{'p', 'H', 'Y', 's'}, []byte{0, 0, 0x0b, 0x13, 0, 0, 0x0b, 0x13, 1})...)
+	buf = append(buf, rawChunk(idatChunkType, []byte{0x01, 0x02, 0x03})...)
+	buf = append(buf, rawChunk(iendChunkType, nil)...)
+	return buf
+}
+
+func TestCopyReplacesExifChunk(t *testing.T) {
+	orig := samplePNG([]byte("old exif data"))
+	x := &exif.Exif{ByteOrder: binary.BigEndian, IFD0: exif.Dir{}}
+	wantExif, err := x.EncodeBytes()
+	if err != nil {
+		t.Fatalf("EncodeBytes: %v", err)
+	}
+
+	var out bytes.Buffer
+	if err := Copy(&out, bytes.NewReader(orig), x); err != nil {
+		t.Fatalf("Copy: %v", err)
+	}
+
+	r := bytes.NewReader(out.Bytes())
+	if err := readSignature(r); err != nil {
+		t.Fatalf("readSignature: %v", err)
+	}
+	var gotExif []byte
+	for {
+		c, err := readChunk(r)
+		if err != nil {
+			t.Fatalf("readChunk: %v", err)
+		}
+		if c.typ == exifChunkType {
+			gotExif = c.data
+		}
+		if c.typ == iendChunkType {
+			break
+		}
+	}
+	if !bytes.Equal(gotExif, wantExif) {
+		t.Fatalf("got eXIf data %x, want %x", gotExif, wantExif)
+	}
+}
+
+func TestCopyPreservesUnrelatedChunksByteForByte(t *testing.T) {
+	orig := samplePNG([]byte("old exif data"))
+
+	var out bytes.Buffer
+	if err := Copy(&out, bytes.NewReader(orig), nil); err != nil {
+		t.Fatalf("Copy: %v", err)
+	}
+
+	rOrig, rOut := bytes.NewReader(orig), bytes.NewReader(out.Bytes())
+	readSignature(rOrig)
+	readSignature(rOut)
+	for {
+		co, errO := readChunk(rOrig)
+		if errO != nil {
+			break
+		}
+		if co.typ == exifChunkType {
+			continue
+		}
+		cn, errN := readChunk(rOut)
+		if errN != nil {
+			t.Fatalf("readChunk(out): %v", errN)
+		}
+		if co.typ != cn.typ || !bytes.Equal(co.data, cn.data) {
+			t.Fatalf("chunk mismatch: got %v %x, want %v %x", cn.typ, cn.data, co.typ, co.data)
+		}
+	}
+}
+
+func TestCopyRecomputesCRC32(t *testing.T) {
+	orig := samplePNG(nil)
+	x := &exif.Exif{ByteOrder: binary.BigEndian, IFD0: exif.Dir{}}
+
+	var out bytes.Buffer
+	if err := Copy(&out, bytes.NewReader(orig), x); err != nil {
+		t.Fatalf("Copy: %v", err)
+	}
+
+	data := out.Bytes()[len(pngSignature):]
+	for len(data) > 0 {
+		length := binary.BigEndian.Uint32(data[:4])
+		typ := data[4:8]
+		body := data[8 : 8+length]
+		wantCRC := binary.BigEndian.Uint32(data[8+length : 12+length])
+
+		crc := crc32.NewIEEE()
+		crc.Write(typ)
+		crc.Write(body)
+		if crc.Sum32() != wantCRC {
+			t.Fatalf("chunk %q: got CRC32 %x, want %x", typ, wantCRC, crc.Sum32())
+		}
+		data = data[12+length:]
+	}
+}
+
+func TestCopyNeverTouchesBytesAfterIEND(t *testing.T) {
+	trailer := []byte("trailing bytes after IEND must survive untouched")
+	orig := append(samplePNG(nil), trailer...)
+
+	var out bytes.Buffer
+	if err := Copy(&out, bytes.NewReader(orig), nil); err != nil {
+		t.Fatalf("Copy: %v", err)
+	}
+	if bytes.Contains(out.Bytes(), trailer) {
+		t.Fatal("trailer after IEND was copied into the output")
+	}
+}
+
+// minimalTIFF is a valid, empty big-endian TIFF/Exif payload (no IFD0
+// entries), usable anywhere a real eXIf chunk body must decode.
+var minimalTIFF = []byte{
+	'M', 'M', 0x00, 0x2a, 0x00, 0x00, 0x00, 0x08, // header, IFD0 at offset 8
+	0x00, 0x00, // 0 IFD0 entries
+	0x00, 0x00, 0x00, 0x00, // next IFD offset
+}
+
+func TestTextRoundTripFullPNG(t *testing.T) {
+	orig := samplePNG(minimalTIFF)
+	entries := []TextEntry{
+		{Keyword: "Comment", Text: "hello"},
+		{Keyword: "Author", Text: "compressed hello", Compressed: true},
+		{Keyword: "Title", Language: "en", Translated: "Titre", Text: "bonjour"},
+	}
+
+	var withText bytes.Buffer
+	if err := EncodeText(&withText, bytes.NewReader(orig), entries); err != nil {
+		t.Fatalf("EncodeText: %v", err)
+	}
+
+	got, err := DecodeText(bytes.NewReader(withText.Bytes()))
+	if err != nil {
+		t.Fatalf("DecodeText: %v", err)
+	}
+	if len(got) != len(entries) {
+		t.Fatalf("got %d entries, want %d: %+v", len(got), len(entries), got)
+	}
+	for i, want := range entries {
+		if got[i] != want {
+			t.Fatalf("entry %d: got %+v, want %+v", i, got[i], want)
+		}
+	}
+
+	// The eXIf chunk must survive EncodeText and still decode.
+	if _, err := Decode(bytes.NewReader(withText.Bytes())); err != nil {
+		t.Fatalf("Decode after EncodeText: %v", err)
+	}
+}
@@ -0,0 +1,200 @@
+// Package png implements a PNG/Exif decoder and encoder, mirroring
+// the exif package's JPEG APIs so callers can treat both container
+// formats uniformly.
+package png
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"io"
+
+	"github.com/tajtiattila/metadata/exif"
+)
+
+var (
+	ErrInvalidFormat = errors.New("png: invalid format")
+	ErrExifNotFound  = errors.New("png: exif data not found")
+	ErrChunkTooLarge = errors.New("png: chunk length exceeds maxChunkSize")
+)
+
+// maxChunkSize bounds the amount of memory readChunk will allocate
+// for a single chunk's data. The on-disk length field is 4 bytes and
+// is not otherwise validated against the input, so without this cap
+// a corrupt or malicious length (e.g. near 2^32-1) would make
+// readChunk attempt a multi-gigabyte allocation before the
+// subsequent read ever gets a chance to fail. The PNG spec itself
+// limits chunk data to 2^31-1 bytes; this is far below that and
+// comfortably above any legitimate Exif or text chunk.
+const maxChunkSize = 256 << 20 // 256 MiB
+
+var pngSignature = []byte{0x89, 'P', 'N', 'G', '\r', '\n', 0x1a, '\n'}
+
+var (
+	ihdrChunkType = [4]byte{'I', 'H', 'D', 'R'}
+	idatChunkType = [4]byte{'I', 'D', 'A', 'T'}
+	iendChunkType = [4]byte{'I', 'E', 'N', 'D'}
+	exifChunkType = [4]byte{'e', 'X', 'I', 'f'}
+)
+
+// chunk holds one PNG chunk's type and data, with its length and
+// CRC32 already stripped off by readChunk.
+type chunk struct {
+	typ  [4]byte
+	data []byte
+}
+
+func readSignature(r io.Reader) error {
+	var sig [8]byte
+	if _, err := io.ReadFull(r, sig[:]); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return ErrInvalidFormat
+		}
+		return err
+	}
+	if !bytes.Equal(sig[:], pngSignature) {
+		return ErrInvalidFormat
+	}
+	return nil
+}
+
+// readChunk reads the next chunk from r. The CRC32 trailing the
+// chunk is read but not verified; writeChunk always recomputes it
+// for chunks this package emits.
+func readChunk(r io.Reader) (chunk, error) {
+	var hdr [8]byte
+	if _, err := io.ReadFull(r, hdr[:]); err != nil {
+		return chunk{}, err
+	}
+
+	var c chunk
+	length := binary.BigEndian.Uint32(hdr[:4])
+	copy(c.typ[:], hdr[4:8])
+
+	if length > maxChunkSize {
+		return chunk{}, ErrChunkTooLarge
+	}
+
+	if length > 0 {
+		c.data = make([]byte, length)
+		if _, err := io.ReadFull(r, c.data); err != nil {
+			return chunk{}, io.ErrUnexpectedEOF
+		}
+	}
+
+	var crc [4]byte
+	if _, err := io.ReadFull(r, crc[:]); err != nil {
+		return chunk{}, io.ErrUnexpectedEOF
+	}
+	return c, nil
+}
+
+func writeChunk(w io.Writer, typ [4]byte, data []byte) error {
+	var lb [4]byte
+	binary.BigEndian.PutUint32(lb[:], uint32(len(data)))
+	if _, err := w.Write(lb[:]); err != nil {
+		return err
+	}
+
+	crc := crc32.NewIEEE()
+	crc.Write(typ[:])
+	crc.Write(data)
+
+	if _, err := w.Write(typ[:]); err != nil {
+		return err
+	}
+	if len(data) > 0 {
+		if _, err := w.Write(data); err != nil {
+			return err
+		}
+	}
+
+	var cb [4]byte
+	binary.BigEndian.PutUint32(cb[:], crc.Sum32())
+	_, err := w.Write(cb[:])
+	return err
+}
+
+// Decode decodes Exif metadata from the eXIf chunk of the PNG image
+// in r.
+func Decode(r io.Reader) (*exif.Exif, error) {
+	if err := readSignature(r); err != nil {
+		return nil, err
+	}
+	for {
+		c, err := readChunk(r)
+		if err != nil {
+			if err == io.EOF {
+				return nil, ErrExifNotFound
+			}
+			return nil, err
+		}
+		switch c.typ {
+		case exifChunkType:
+			return exif.DecodeBytes(c.data)
+		case iendChunkType:
+			return nil, ErrExifNotFound
+		}
+	}
+}
+
+// Copy copies the PNG image in r to w, replacing its eXIf chunk with
+// the Exif metadata in x. If x is nil, no eXIf chunk is written; the
+// original eXIf chunk, if any, is always discarded. All other chunks
+// are preserved unchanged, with their CRC32 recomputed from their
+// (unmodified) type and data. Copy stops at IEND and never reads or
+// writes past it.
+func Copy(w io.Writer, r io.Reader, x *exif.Exif) error {
+	if err := readSignature(r); err != nil {
+		return err
+	}
+	if _, err := w.Write(pngSignature); err != nil {
+		return err
+	}
+
+	var exifdata []byte
+	if x != nil {
+		var err error
+		exifdata, err = x.EncodeBytes()
+		if err != nil {
+			return err
+		}
+	}
+
+	wroteExif := false
+	for {
+		c, err := readChunk(r)
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+
+		if c.typ == exifChunkType {
+			// the original chunk is discarded; a replacement, if
+			// any, is written right after IHDR below
+			continue
+		}
+
+		if err := writeChunkRaw(w, c); err != nil {
+			return err
+		}
+
+		if c.typ == iendChunkType {
+			return nil
+		}
+
+		if !wroteExif && exifdata != nil && c.typ == ihdrChunkType {
+			if err := writeChunk(w, exifChunkType, exifdata); err != nil {
+				return err
+			}
+			wroteExif = true
+		}
+	}
+}
+
+func writeChunkRaw(w io.Writer, c chunk) error {
+	return writeChunk(w, c.typ, c.data)
+}
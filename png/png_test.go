@@ -0,0 +1,32 @@
+package png
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestReadChunkRejectsOversizedLength(t *testing.T) {
+	// length = 0xffffffff, type "IDAT", no data, no (valid) CRC: a
+	// tiny, clearly bogus input that would otherwise make readChunk
+	// allocate gigabytes before the subsequent read ever fails.
+	hdr := []byte{0xff, 0xff, 0xff, 0xff, 'I', 'D', 'A', 'T'}
+	if _, err := readChunk(bytes.NewReader(hdr)); err != ErrChunkTooLarge {
+		t.Fatalf("readChunk: got err=%v, want ErrChunkTooLarge", err)
+	}
+}
+
+func TestReadChunkRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	want := []byte("hello")
+	if err := writeChunk(&buf, exifChunkType, want); err != nil {
+		t.Fatalf("writeChunk: %v", err)
+	}
+
+	c, err := readChunk(&buf)
+	if err != nil {
+		t.Fatalf("readChunk: %v", err)
+	}
+	if c.typ != exifChunkType || !bytes.Equal(c.data, want) {
+		t.Fatalf("got %+v, want type=%v data=%q", c, exifChunkType, want)
+	}
+}
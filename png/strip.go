@@ -0,0 +1,88 @@
+package png
+
+import (
+	"io"
+
+	"github.com/tajtiattila/metadata/exif"
+)
+
+var icCPChunkType = [4]byte{'i', 'C', 'C', 'P'}
+
+// Strip copies the PNG image in r to w, keeping only the chunks
+// needed to decode and render it (IHDR, PLTE, IDAT, IEND, and a
+// small set of rendering-related ancillary chunks: tRNS, gAMA, cHRM,
+// sRGB, sBIT, bKGD, hIST, pHYs and sPLT) and dropping everything
+// else, including eXIf, tEXt/zTXt/iTXt, tIME and any chunk Strip
+// does not recognise. Like exif.Strip, this is a positive whitelist,
+// so unexpected ancillary chunks that might carry personal data are
+// removed by default. opts mirrors exif.StripOptions: KeepICC
+// preserves the iCCP chunk, and KeepOrientation re-encodes the eXIf
+// chunk, if present, down to just its Orientation tag instead of
+// dropping it entirely.
+func Strip(w io.Writer, r io.Reader, opts exif.StripOptions) error {
+	if err := readSignature(r); err != nil {
+		return err
+	}
+	if _, err := w.Write(pngSignature); err != nil {
+		return err
+	}
+
+	for {
+		c, err := readChunk(r)
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+
+		switch {
+		case isDecodeChunk(c.typ):
+			// keep as-is
+
+		case opts.KeepICC && c.typ == icCPChunkType:
+			// keep as-is
+
+		case opts.KeepOrientation && c.typ == exifChunkType:
+			data, ok, err := exif.OrientationOnlyBytes(c.data)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				continue
+			}
+			c.data = data
+
+		default:
+			// unrecognised, or explicitly excluded, chunk: drop it
+			continue
+		}
+
+		if err := writeChunkRaw(w, c); err != nil {
+			return err
+		}
+		if c.typ == iendChunkType {
+			return nil
+		}
+	}
+}
+
+// isDecodeChunk reports whether typ is a critical chunk, or one of a
+// small set of ancillary chunks needed to render the image correctly
+// (transparency, gamma and color/rendering hints).
+func isDecodeChunk(typ [4]byte) bool {
+	switch typ {
+	case ihdrChunkType, [4]byte{'P', 'L', 'T', 'E'}, idatChunkType, iendChunkType,
+		[4]byte{'t', 'R', 'N', 'S'},
+		[4]byte{'g', 'A', 'M', 'A'},
+		[4]byte{'c', 'H', 'R', 'M'},
+		[4]byte{'s', 'R', 'G', 'B'},
+		[4]byte{'s', 'B', 'I', 'T'},
+		[4]byte{'b', 'K', 'G', 'D'},
+		[4]byte{'h', 'I', 'S', 'T'},
+		[4]byte{'p', 'H', 'Y', 's'},
+		[4]byte{'s', 'P', 'L', 'T'}:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,290 @@
+package png
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"io"
+	"io/ioutil"
+)
+
+var (
+	textChunkType = [4]byte{'t', 'E', 'X', 't'}
+	ztxtChunkType = [4]byte{'z', 'T', 'X', 't'}
+	itxtChunkType = [4]byte{'i', 'T', 'X', 't'}
+)
+
+var (
+	// ErrTextFormat is returned by DecodeText when a tEXt, zTXt or
+	// iTXt chunk is malformed.
+	ErrTextFormat = errors.New("png: malformed text chunk")
+
+	// ErrTextTooLarge is returned by DecodeText when a zTXt or
+	// compressed iTXt chunk decompresses to more than maxTextSize.
+	ErrTextTooLarge = errors.New("png: decompressed text exceeds maxTextSize")
+)
+
+// maxTextSize bounds the decompressed size of a zTXt or compressed
+// iTXt chunk's text. Without this cap, a tiny compressed chunk could
+// decompress to an arbitrarily large allocation (a zlib bomb); this
+// is far above any legitimate textual metadata value.
+const maxTextSize = 16 << 20 // 16 MiB
+
+// TextEntry holds one item of PNG textual metadata, read from or
+// written to a tEXt, zTXt or iTXt chunk.
+type TextEntry struct {
+	Keyword string
+
+	// Language and Translated are only meaningful for iTXt entries;
+	// an entry is encoded as iTXt whenever either is non-empty.
+	Language   string
+	Translated string
+
+	Text string
+
+	// Compressed requests zTXt (or, for iTXt entries, compressed
+	// iTXt) encoding.
+	Compressed bool
+}
+
+// DecodeText reads all textual metadata (tEXt, zTXt and iTXt chunks)
+// from the PNG image in r, in the order they appear.
+func DecodeText(r io.Reader) ([]TextEntry, error) {
+	if err := readSignature(r); err != nil {
+		return nil, err
+	}
+
+	var entries []TextEntry
+	for {
+		c, err := readChunk(r)
+		if err != nil {
+			if err == io.EOF {
+				return entries, nil
+			}
+			return nil, err
+		}
+
+		var e TextEntry
+		switch c.typ {
+		case textChunkType:
+			e, err = decodeTEXt(c.data)
+		case ztxtChunkType:
+			e, err = decodeZTXt(c.data)
+		case itxtChunkType:
+			e, err = decodeITXt(c.data)
+		case iendChunkType:
+			return entries, nil
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+}
+
+// EncodeText copies the PNG image in r to w, replacing all textual
+// metadata (tEXt, zTXt and iTXt chunks) with entries. All other
+// chunks are preserved unchanged. The replacement chunks are written
+// immediately before the first IDAT chunk.
+func EncodeText(w io.Writer, r io.Reader, entries []TextEntry) error {
+	if err := readSignature(r); err != nil {
+		return err
+	}
+	if _, err := w.Write(pngSignature); err != nil {
+		return err
+	}
+
+	wrote := false
+	for {
+		c, err := readChunk(r)
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+
+		switch c.typ {
+		case textChunkType, ztxtChunkType, itxtChunkType:
+			continue
+		case idatChunkType, iendChunkType:
+			if !wrote {
+				if err := writeTextEntries(w, entries); err != nil {
+					return err
+				}
+				wrote = true
+			}
+		}
+
+		if err := writeChunkRaw(w, c); err != nil {
+			return err
+		}
+		if c.typ == iendChunkType {
+			return nil
+		}
+	}
+}
+
+func writeTextEntries(w io.Writer, entries []TextEntry) error {
+	for _, e := range entries {
+		typ, data, err := encodeTextEntry(e)
+		if err != nil {
+			return err
+		}
+		if err := writeChunk(w, typ, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func decodeTEXt(data []byte) (TextEntry, error) {
+	keyword, text, ok := cutNUL(data)
+	if !ok {
+		return TextEntry{}, ErrTextFormat
+	}
+	return TextEntry{Keyword: string(keyword), Text: string(text)}, nil
+}
+
+func decodeZTXt(data []byte) (TextEntry, error) {
+	keyword, rest, ok := cutNUL(data)
+	if !ok || len(rest) < 1 {
+		return TextEntry{}, ErrTextFormat
+	}
+	if rest[0] != 0 {
+		return TextEntry{}, ErrTextFormat // unknown compression method
+	}
+	text, err := inflate(rest[1:])
+	if err != nil {
+		return TextEntry{}, err
+	}
+	return TextEntry{Keyword: string(keyword), Text: string(text), Compressed: true}, nil
+}
+
+func decodeITXt(data []byte) (TextEntry, error) {
+	keyword, rest, ok := cutNUL(data)
+	if !ok || len(rest) < 2 {
+		return TextEntry{}, ErrTextFormat
+	}
+	compressed, method, rest := rest[0] != 0, rest[1], rest[2:]
+	if compressed && method != 0 {
+		return TextEntry{}, ErrTextFormat
+	}
+
+	lang, rest, ok := cutNUL(rest)
+	if !ok {
+		return TextEntry{}, ErrTextFormat
+	}
+	translated, rest, ok := cutNUL(rest)
+	if !ok {
+		return TextEntry{}, ErrTextFormat
+	}
+
+	text := rest
+	if compressed {
+		var err error
+		text, err = inflate(rest)
+		if err != nil {
+			return TextEntry{}, err
+		}
+	}
+
+	return TextEntry{
+		Keyword:    string(keyword),
+		Language:   string(lang),
+		Translated: string(translated),
+		Text:       string(text),
+		Compressed: compressed,
+	}, nil
+}
+
+func encodeTextEntry(e TextEntry) ([4]byte, []byte, error) {
+	if e.Language != "" || e.Translated != "" {
+		return encodeITXt(e)
+	}
+	if e.Compressed {
+		return encodeZTXt(e)
+	}
+	data := append([]byte(e.Keyword), 0)
+	data = append(data, e.Text...)
+	return textChunkType, data, nil
+}
+
+func encodeZTXt(e TextEntry) ([4]byte, []byte, error) {
+	compressed, err := deflate([]byte(e.Text))
+	if err != nil {
+		return [4]byte{}, nil, err
+	}
+	data := append([]byte(e.Keyword), 0, 0) // keyword, NUL, compression method 0
+	data = append(data, compressed...)
+	return ztxtChunkType, data, nil
+}
+
+func encodeITXt(e TextEntry) ([4]byte, []byte, error) {
+	text := []byte(e.Text)
+	var flag byte
+	if e.Compressed {
+		var err error
+		text, err = deflate(text)
+		if err != nil {
+			return [4]byte{}, nil, err
+		}
+		flag = 1
+	}
+
+	data := append([]byte(e.Keyword), 0, flag, 0) // keyword NUL, compression flag, compression method 0
+	data = appendNUL(data, e.Language, e.Translated)
+	data = append(data, text...)
+	return itxtChunkType, data, nil
+}
+
+// cutNUL splits data at the first NUL byte, as used to delimit the
+// keyword (and, for iTXt, the language tag and translated keyword)
+// fields of text chunks.
+func cutNUL(data []byte) (before, after []byte, ok bool) {
+	i := bytes.IndexByte(data, 0)
+	if i < 0 {
+		return nil, nil, false
+	}
+	return data[:i], data[i+1:], true
+}
+
+func appendNUL(data []byte, fields ...string) []byte {
+	for _, f := range fields {
+		data = append(data, f...)
+		data = append(data, 0)
+	}
+	return data
+}
+
+func inflate(p []byte) ([]byte, error) {
+	zr, err := zlib.NewReader(bytes.NewReader(p))
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+
+	lr := &io.LimitedReader{R: zr, N: maxTextSize + 1}
+	data, err := ioutil.ReadAll(lr)
+	if err != nil {
+		return nil, err
+	}
+	if lr.N <= 0 {
+		return nil, ErrTextTooLarge
+	}
+	return data, nil
+}
+
+func deflate(p []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := zlib.NewWriter(&buf)
+	if _, err := zw.Write(p); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
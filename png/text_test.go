@@ -0,0 +1,47 @@
+package png
+
+import "testing"
+
+func TestInflateRejectsOversizedOutput(t *testing.T) {
+	huge := make([]byte, maxTextSize+1) // all zero bytes: compresses tiny
+	compressed, err := deflate(huge)
+	if err != nil {
+		t.Fatalf("deflate: %v", err)
+	}
+
+	if _, err := inflate(compressed); err != ErrTextTooLarge {
+		t.Fatalf("inflate: got err=%v, want ErrTextTooLarge", err)
+	}
+}
+
+func TestTextEntryRoundTrip(t *testing.T) {
+	cases := []TextEntry{
+		{Keyword: "Comment", Text: "hello"},
+		{Keyword: "Comment", Text: "compressed hello", Compressed: true},
+		{Keyword: "Comment", Language: "en", Translated: "Commentaire", Text: "bonjour"},
+		{Keyword: "Comment", Language: "en", Translated: "Commentaire", Text: "bonjour compresse", Compressed: true},
+	}
+
+	for _, want := range cases {
+		typ, data, err := encodeTextEntry(want)
+		if err != nil {
+			t.Fatalf("encodeTextEntry(%+v): %v", want, err)
+		}
+
+		var got TextEntry
+		switch typ {
+		case textChunkType:
+			got, err = decodeTEXt(data)
+		case ztxtChunkType:
+			got, err = decodeZTXt(data)
+		case itxtChunkType:
+			got, err = decodeITXt(data)
+		}
+		if err != nil {
+			t.Fatalf("decode %v(%+v): %v", typ, want, err)
+		}
+		if got != want {
+			t.Fatalf("round trip mismatch: got %+v, want %+v", got, want)
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package metadata
+
+import (
+	"io"
+
+	xjpeg "github.com/tajtiattila/metadata/jpeg"
+)
+
+// SegmentAction tells Rewriter what to do with a segment after a
+// SegmentHandler has inspected it.
+type SegmentAction int
+
+const (
+	// Keep writes the segment unchanged.
+	Keep SegmentAction = iota
+	// Drop omits the segment entirely.
+	Drop
+	// Replace writes SegmentResult.Data instead of the segment.
+	Replace
+)
+
+// SegmentResult is returned by a SegmentHandler to tell Rewriter what
+// to do with the segment it was given.
+type SegmentResult struct {
+	Action SegmentAction
+	Data   []byte // replacement bytes, used only when Action is Replace
+}
+
+// SegmentHandler inspects a single JPEG marker segment and decides
+// whether Rewriter keeps, drops or replaces it. chunk holds the
+// segment's marker, length and payload bytes, as returned by
+// xjpeg.Scanner.ReadChunk. A handler that builds Data by editing
+// chunk in place can use FileOps to describe the edit and FileOps.Copy
+// to apply it.
+type SegmentHandler func(marker byte, chunk []byte) SegmentResult
+
+// Rewriter composes several metadata mutations into a single forward
+// pass over a JPEG source. Unlike exif.Copy, which buffers and
+// reorders segments before writing them, Rewriter streams its output
+// as it reads and never seeks, so it needs no more memory than the
+// largest single segment and can operate on pipes and very large
+// files.
+type Rewriter struct {
+	handlers []segmentHandlerReg
+}
+
+// segmentHandlerReg pairs a SegmentHandler with the signature of the
+// segments it handles.
+type segmentHandlerReg struct {
+	sig []byte
+	fn  SegmentHandler
+}
+
+// NewRewriter returns a Rewriter with no handlers registered.
+// Segments with no matching handler are kept unchanged.
+func NewRewriter() *Rewriter {
+	return &Rewriter{}
+}
+
+// Handle registers handler for segments whose chunk bytes (marker,
+// length and payload, as passed to a SegmentHandler) match sig: sig[0]
+// must be 0xff, sig[1] is the marker byte, sig[2:4] are wildcarded as
+// the segment's length, and any further bytes are compared against
+// the payload. This lets several handlers share a marker byte, as
+// long as they are distinguished by what follows it — for example
+// Exif and XMP both live in APP1 (marker 0xe1), so registering one
+// handler for "\xff\xe1--Exif\x00\x00" and another for
+// "\xff\xe1--http://ns.adobe.com/xap/1.0/\x00" lets Rewrite compose
+// an Exif replace and an XMP replace in the same pass. A bare
+// marker, such as []byte{0xff, 0xfe} for COM, matches every segment
+// with that marker.
+//
+// Handlers are tried in registration order; the first whose sig
+// matches handles the segment, and later registrations for the same
+// sig do not replace earlier ones.
+func (rw *Rewriter) Handle(sig []byte, handler SegmentHandler) {
+	rw.handlers = append(rw.handlers, segmentHandlerReg{sig: sig, fn: handler})
+}
+
+// lookup returns the first registered handler whose signature matches
+// chunk, the full marker+length+payload bytes of a segment.
+func (rw *Rewriter) lookup(chunk []byte) (SegmentHandler, bool) {
+	for _, h := range rw.handlers {
+		if cmpSegmentHeader(chunk, h.sig) {
+			return h.fn, true
+		}
+	}
+	return nil, false
+}
+
+// cmpSegmentHeader reports whether chunk starts with header, ignoring
+// header's 2-byte length field at indices 2 and 3.
+func cmpSegmentHeader(chunk, header []byte) bool {
+	if len(chunk) < len(header) {
+		return false
+	}
+	for i := range header {
+		if i == 2 || i == 3 {
+			continue
+		}
+		if chunk[i] != header[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Rewrite streams r to w, passing every marker segment to its
+// registered SegmentHandler, if any, and copies the remaining bytes,
+// such as the scan data, unchanged.
+func (rw *Rewriter) Rewrite(w io.Writer, r io.Reader) error {
+	j, err := xjpeg.NewScanner(r)
+	if err != nil {
+		return err
+	}
+
+	if _, err := w.Write([]byte{0xff, 0xd8}); err != nil {
+		return err
+	}
+
+	for j.Next() {
+		chunk, err := j.ReadChunk()
+		if err != nil {
+			return err
+		}
+		if len(chunk) < 2 {
+			if _, err := w.Write(chunk); err != nil {
+				return err
+			}
+			continue
+		}
+		marker := chunk[1]
+
+		handler, ok := rw.lookup(chunk)
+		if !ok {
+			if _, err := w.Write(chunk); err != nil {
+				return err
+			}
+			continue
+		}
+
+		switch res := handler(marker, chunk); res.Action {
+		case Drop:
+			// write nothing
+		case Replace:
+			if _, err := w.Write(res.Data); err != nil {
+				return err
+			}
+		default: // Keep
+			if _, err := w.Write(chunk); err != nil {
+				return err
+			}
+		}
+	}
+	if err := j.Err(); err != nil {
+		return err
+	}
+
+	_, err = io.Copy(w, j.Reader())
+	return err
+}
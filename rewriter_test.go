@@ -0,0 +1,56 @@
+package metadata
+
+import "testing"
+
+var (
+	testExifSig = []byte("\xff\xe1--Exif\x00\x00")
+	testXMPSig  = []byte("\xff\xe1--http://ns.adobe.com/xap/1.0/\x00")
+)
+
+func TestRewriterHandleComposesSharedMarker(t *testing.T) {
+	rw := NewRewriter()
+
+	var exifSeen, xmpSeen bool
+	rw.Handle(testExifSig, func(marker byte, chunk []byte) SegmentResult {
+		exifSeen = true
+		return SegmentResult{Action: Drop}
+	})
+	rw.Handle(testXMPSig, func(marker byte, chunk []byte) SegmentResult {
+		xmpSeen = true
+		return SegmentResult{Action: Drop}
+	})
+
+	exifChunk := append([]byte{0xff, 0xe1, 0x00, 0x0c}, []byte("Exif\x00\x00\x01\x02\x03\x04")...)
+	handler, ok := rw.lookup(exifChunk)
+	if !ok {
+		t.Fatal("no handler matched the Exif segment")
+	}
+	handler(0xe1, exifChunk)
+	if !exifSeen || xmpSeen {
+		t.Fatalf("expected only the Exif handler to run, got exif=%v xmp=%v", exifSeen, xmpSeen)
+	}
+
+	exifSeen, xmpSeen = false, false
+	xmpChunk := append([]byte{0xff, 0xe1, 0x00, 0x23}, []byte("http://ns.adobe.com/xap/1.0/\x00<x/>")...)
+	handler, ok = rw.lookup(xmpChunk)
+	if !ok {
+		t.Fatal("no handler matched the XMP segment")
+	}
+	handler(0xe1, xmpChunk)
+	if exifSeen || !xmpSeen {
+		t.Fatalf("expected only the XMP handler to run, got exif=%v xmp=%v", exifSeen, xmpSeen)
+	}
+}
+
+func TestRewriterHandleUnmatchedMarkerFallsThrough(t *testing.T) {
+	rw := NewRewriter()
+	rw.Handle(testExifSig, func(marker byte, chunk []byte) SegmentResult {
+		t.Fatal("handler should not run for a non-matching segment")
+		return SegmentResult{}
+	})
+
+	comChunk := []byte{0xff, 0xfe, 0x00, 0x04, 'h', 'i'}
+	if _, ok := rw.lookup(comChunk); ok {
+		t.Fatal("expected no handler to match a COM segment")
+	}
+}